@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ------------------------------------------------------------
+// event hub (pub/sub)
+// ------------------------------------------------------------
+
+// Event is a single status-transition or lifecycle notice pushed to
+// every subscriber of /api/events.
+type Event struct {
+	Type      string      `json:"type"` // "health", "lifecycle", "strategy", "request", "log"
+	Backend   string      `json:"backend,omitempty"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Hub fans events out to any number of WebSocket subscribers.
+// Listeners register a channel and the hub never blocks sending to a
+// slow or dead one.
+type Hub struct {
+	mu        sync.Mutex
+	listeners map[chan Event]struct{}
+}
+
+func newHub() *Hub {
+	return &Hub{listeners: make(map[chan Event]struct{})}
+}
+
+func (h *Hub) subscribe() chan Event {
+	ch := make(chan Event, 32)
+	h.mu.Lock()
+	h.listeners[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *Hub) unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	delete(h.listeners, ch)
+	h.mu.Unlock()
+	close(ch)
+}
+
+func (h *Hub) publish(e Event) {
+	e.Timestamp = time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.listeners {
+		select {
+		case ch <- e:
+		default:
+			// slow subscriber, drop the event rather than block the publisher
+		}
+	}
+}
+
+// ------------------------------------------------------------
+// per-backend log ring buffer
+// ------------------------------------------------------------
+
+// logRingBuffer keeps the last N lines of a backend's stdout/stderr
+// in memory for /api/logs/{backend}?tail=N, and fans each line out
+// to any subscribed WebSocket clients.
+type logRingBuffer struct {
+	mu       sync.Mutex
+	lines    []string
+	capacity int
+	hub      *Hub
+	backend  string
+}
+
+func newLogRingBuffer(backend string, capacity int, hub *Hub) *logRingBuffer {
+	if capacity <= 0 {
+		capacity = 500
+	}
+	return &logRingBuffer{capacity: capacity, hub: hub, backend: backend}
+}
+
+// Write implements io.Writer so it can be plugged in as Cmd.Stdout/Stderr.
+func (rb *logRingBuffer) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		rb.append(line)
+	}
+	return len(p), nil
+}
+
+func (rb *logRingBuffer) append(line string) {
+	rb.mu.Lock()
+	rb.lines = append(rb.lines, line)
+	if len(rb.lines) > rb.capacity {
+		rb.lines = rb.lines[len(rb.lines)-rb.capacity:]
+	}
+	rb.mu.Unlock()
+
+	if rb.hub != nil {
+		rb.hub.publish(Event{Type: "log", Backend: rb.backend, Message: line})
+	}
+}
+
+func (rb *logRingBuffer) tail(n int) []string {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if n <= 0 || n > len(rb.lines) {
+		n = len(rb.lines)
+	}
+	out := make([]string, n)
+	copy(out, rb.lines[len(rb.lines)-n:])
+	return out
+}
+
+// ------------------------------------------------------------
+// websocket endpoints
+// ------------------------------------------------------------
+
+// EventsConfig controls which origins may open the live WebSocket
+// endpoints (/api/events, /api/logs/*, /api/backends/*). Requests
+// with no Origin header at all (curl, native clients) are always let
+// through. Any request that carries an Origin header, which browsers
+// send on every WebSocket handshake, same-origin pages included, is
+// let in only when that origin is listed; left empty, none is.
+type EventsConfig struct {
+	AllowedOrigins []string `json:"allowedOrigins"`
+}
+
+var allowedOrigins map[string]bool
+
+// configureOrigins is called once from main with the configured
+// EventsConfig before the HTTP handlers are registered.
+func configureOrigins(origins []string) {
+	allowedOrigins = make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowedOrigins[o] = true
+	}
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkWSOrigin,
+}
+
+// checkWSOrigin rejects cross-site WebSocket upgrades from origins
+// that aren't explicitly allowlisted. Without this, any page in a
+// victim's browser could open these sockets and read live backend
+// status/log data (cross-site WebSocket hijacking).
+func checkWSOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	return allowedOrigins[origin]
+}
+
+// handleEventsWS streams status transitions, lifecycle changes, and
+// strategy changes to a connected UI client.
+func handleEventsWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Errorw("events ws upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := eventHub.subscribe()
+	defer eventHub.unsubscribe(ch)
+
+	for e := range ch {
+		if err := conn.WriteJSON(e); err != nil {
+			return
+		}
+	}
+}
+
+// handleBackendLogs serves /api/backends/{name}/logs?tail=N as a
+// plain JSON snapshot, and /api/logs/{name} as a live WebSocket
+// stream of the same backend's stdout/stderr.
+func handleBackendLogs(w http.ResponseWriter, r *http.Request) {
+	name := backendNameFromLogsPath(r.URL.Path)
+
+	mu.RLock()
+	var rb *logRingBuffer
+	for _, b := range backends {
+		if b.Name == name {
+			rb = b.Logs
+			break
+		}
+	}
+	mu.RUnlock()
+
+	if rb == nil {
+		http.Error(w, "unknown backend", http.StatusNotFound)
+		return
+	}
+
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		tail := 100
+		if v := r.URL.Query().Get("tail"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				tail = n
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"lines": rb.tail(tail)})
+		return
+	}
+
+	handleBackendLogsWS(w, r, name, rb)
+}
+
+func backendNameFromLogsPath(path string) string {
+	name := strings.TrimPrefix(path, "/api/logs/")
+	name = strings.TrimPrefix(name, "/api/backends/")
+	name = strings.TrimSuffix(name, "/logs")
+	name = strings.TrimSuffix(name, "/")
+	return name
+}
+
+func handleBackendLogsWS(w http.ResponseWriter, r *http.Request, name string, rb *logRingBuffer) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Errorw("logs ws upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	for _, line := range rb.tail(100) {
+		if err := conn.WriteJSON(map[string]string{"line": line}); err != nil {
+			return
+		}
+	}
+
+	ch := eventHub.subscribe()
+	defer eventHub.unsubscribe(ch)
+
+	for e := range ch {
+		if e.Type != "log" || e.Backend != name {
+			continue
+		}
+		if err := conn.WriteJSON(map[string]string{"line": e.Message}); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoConfig configures the optional GeoIP subsystem: where to find
+// the MaxMind database and which upstream proxies are trusted to set
+// X-Forwarded-For (so a client can't just spoof their own country).
+type GeoConfig struct {
+	MMDBPath          string   `json:"mmdbPath"`
+	TrustedProxyCIDRs []string `json:"trustedProxyCIDRs"`
+}
+
+// GeoIP resolves a client IP to a country/continent using a MaxMind
+// mmdb. It's nil (disabled) when no mmdbPath is configured.
+type GeoIP struct {
+	db             *geoip2.Reader
+	trustedProxies []*net.IPNet
+}
+
+func newGeoIP(cfg GeoConfig) (*GeoIP, error) {
+	if cfg.MMDBPath == "" {
+		return nil, nil
+	}
+
+	db, err := geoip2.Open(cfg.MMDBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var trusted []*net.IPNet
+	for _, cidr := range cfg.TrustedProxyCIDRs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Warnw("ignoring invalid trustedProxyCIDR", "cidr", cidr, "error", err)
+			continue
+		}
+		trusted = append(trusted, n)
+	}
+
+	return &GeoIP{db: db, trustedProxies: trusted}, nil
+}
+
+// ResolveClientIP returns the IP that should be geo-resolved for a
+// request: the XFF value only if RemoteAddr is one of the trusted
+// proxies, otherwise RemoteAddr itself.
+func (g *GeoIP) ResolveClientIP(r *http.Request) string {
+	remoteHost, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteHost = r.RemoteAddr
+	}
+
+	if g.isTrustedProxy(remoteHost) {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return firstForwardedIP(fwd)
+		}
+	}
+	return remoteHost
+}
+
+func (g *GeoIP) isTrustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range g.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstForwardedIP(xff string) string {
+	for i := 0; i < len(xff); i++ {
+		if xff[i] == ',' {
+			return xff[:i]
+		}
+	}
+	return xff
+}
+
+// Country returns the ISO country code for an IP, or "" if it
+// couldn't be resolved.
+func (g *GeoIP) Country(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	record, err := g.db.Country(parsed)
+	if err != nil {
+		return ""
+	}
+	return record.Country.IsoCode
+}
+
+// Continent returns the continent code for an IP, or "" if it
+// couldn't be resolved.
+func (g *GeoIP) Continent(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	record, err := g.db.Country(parsed)
+	if err != nil {
+		return ""
+	}
+	return record.Continent.Code
+}
+
+// ------------------------------------------------------------
+// geo selection policy
+// ------------------------------------------------------------
+
+// geoPolicy prefers backends in the client's country, then the
+// client's continent, then falls back to least-connections among
+// whatever's left.
+type geoPolicy struct{}
+
+func (geoPolicy) Name() LoadBalancingStrategy { return StrategyGeo }
+
+func (geoPolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	if geoip == nil {
+		return leastConnectionsPolicy{}.Select(backends, r)
+	}
+
+	ip := geoip.ResolveClientIP(r)
+	country := geoip.Country(ip)
+	continent := geoip.Continent(ip)
+
+	if country != "" {
+		if matches := filterBackends(backends, func(b *Backend) bool { return b.Country == country }); len(matches) > 0 {
+			return leastConnectionsPolicy{}.Select(matches, r)
+		}
+	}
+	if continent != "" {
+		if matches := filterBackends(backends, func(b *Backend) bool { return b.Continent == continent }); len(matches) > 0 {
+			return leastConnectionsPolicy{}.Select(matches, r)
+		}
+	}
+	return leastConnectionsPolicy{}.Select(backends, r)
+}
+
+func filterBackends(backends []*Backend, keep func(*Backend) bool) []*Backend {
+	var out []*Backend
+	for _, b := range backends {
+		if keep(b) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------------
+// config
+// ------------------------------------------------------------
+
+// Duration wraps time.Duration so it can be configured in config.json
+// as a plain string like "10s" instead of a nanosecond count.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		d.Duration = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Duration.String())
+}
+
+// ActiveCheckConfig describes an HTTP health probe that is run on a
+// fixed interval against a backend, independent of live traffic.
+type ActiveCheckConfig struct {
+	Path           string   `json:"path"`
+	ExpectedStatus string   `json:"expectedStatus"` // regex, e.g. "^2\\d\\d$"
+	ExpectedBody   string   `json:"expectedBody"`    // substring, optional
+	Interval       Duration `json:"interval"`
+	Timeout        Duration `json:"timeout"`
+}
+
+// PassiveCheckConfig controls how failures observed on real traffic
+// mark a backend unhealthy without waiting for the next active probe.
+type PassiveCheckConfig struct {
+	FailureThreshold int      `json:"failureThreshold"` // consecutive failures before tripping
+	Window           Duration `json:"window"`           // rolling window the failures must fall within
+	RetryAfter       Duration `json:"retryAfter"`        // how long to wait before re-probing
+}
+
+// ------------------------------------------------------------
+// result tracking
+// ------------------------------------------------------------
+
+// CheckResult is the outcome of the most recent active or passive
+// health check for a backend, surfaced via /api/status.
+type CheckResult struct {
+	Healthy   bool          `json:"healthy"`
+	Latency   time.Duration `json:"-"`
+	CheckedAt time.Time     `json:"checkedAt"`
+	Err       string        `json:"error,omitempty"`
+}
+
+// MarshalJSON surfaces Latency as whole milliseconds under the
+// latencyMs key instead of time.Duration's raw nanosecond count,
+// which is what every consumer of /api/status expects.
+func (c CheckResult) MarshalJSON() ([]byte, error) {
+	type alias CheckResult
+	return json.Marshal(struct {
+		alias
+		LatencyMs int64 `json:"latencyMs"`
+	}{alias: alias(c), LatencyMs: c.Latency.Milliseconds()})
+}
+
+// ------------------------------------------------------------
+// checker
+// ------------------------------------------------------------
+
+// HealthChecker owns both the active probing loop and the passive
+// failure bookkeeping for every backend. Every active and passive
+// check result goes through here so handleRequest and the active
+// probes agree on when a backend flips healthy/unhealthy; the one
+// other writer is handleStopBackend, which marks a manually stopped
+// backend unhealthy directly.
+type HealthChecker struct{}
+
+func newHealthChecker() *HealthChecker {
+	return &HealthChecker{}
+}
+
+// Run starts one active-check goroutine per backend that declares an
+// ActiveCheck policy, plus a fallback TCP-dial loop for backends that
+// don't. It returns immediately; the loops run until the process
+// exits.
+func (hc *HealthChecker) Run(bs []*Backend) {
+	for _, b := range bs {
+		b := b
+		if b.ActiveCheck != nil {
+			go hc.activeLoop(b)
+		} else {
+			go hc.tcpLoop(b)
+		}
+	}
+}
+
+func (hc *HealthChecker) activeLoop(b *Backend) {
+	interval := b.ActiveCheck.Interval.Duration
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		hc.probe(b)
+		time.Sleep(interval)
+	}
+}
+
+func (hc *HealthChecker) probe(b *Backend) {
+	ac := b.ActiveCheck
+	timeout := ac.Timeout.Duration
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	u := *b.URL
+	u.Path = ac.Path
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	client := &http.Client{Timeout: timeout}
+	start := time.Now()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		hc.record(b, CheckResult{Healthy: false, CheckedAt: start, Err: err.Error()})
+		return
+	}
+
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		hc.record(b, CheckResult{Healthy: false, Latency: latency, CheckedAt: start, Err: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if ac.ExpectedStatus != "" {
+		matched, err := regexp.MatchString(ac.ExpectedStatus, fmt.Sprintf("%d", resp.StatusCode))
+		if err != nil || !matched {
+			hc.record(b, CheckResult{Healthy: false, Latency: latency, CheckedAt: start,
+				Err: fmt.Sprintf("unexpected status %d", resp.StatusCode)})
+			return
+		}
+	}
+	if ac.ExpectedBody != "" && !strings.Contains(string(body), ac.ExpectedBody) {
+		hc.record(b, CheckResult{Healthy: false, Latency: latency, CheckedAt: start,
+			Err: "response body missing expected substring"})
+		return
+	}
+
+	hc.record(b, CheckResult{Healthy: true, Latency: latency, CheckedAt: start})
+}
+
+// tcpLoop preserves the original socket-only behaviour for backends
+// that don't configure an ActiveCheck.
+func (hc *HealthChecker) tcpLoop(b *Backend) {
+	for {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", b.URL.Host, 800*time.Millisecond)
+		if err != nil {
+			hc.record(b, CheckResult{Healthy: false, Latency: time.Since(start), CheckedAt: start, Err: err.Error()})
+		} else {
+			conn.Close()
+			hc.record(b, CheckResult{Healthy: true, Latency: time.Since(start), CheckedAt: start})
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+func (hc *HealthChecker) record(b *Backend, res CheckResult) {
+	b.healthMu.Lock()
+	defer b.healthMu.Unlock()
+
+	wasHealthy := b.Healthy
+	b.LastCheckResult = res
+	if res.Healthy {
+		b.FailureTimes = nil
+		b.ConsecutiveFailures = 0
+		b.Healthy = true
+		b.LastSeen = res.CheckedAt
+	} else {
+		window := 30 * time.Second
+		threshold := 1
+		if b.PassiveCheck != nil {
+			if b.PassiveCheck.Window.Duration > 0 {
+				window = b.PassiveCheck.Window.Duration
+			}
+			if b.PassiveCheck.FailureThreshold > 0 {
+				threshold = b.PassiveCheck.FailureThreshold
+			}
+		}
+
+		b.FailureTimes = append(b.FailureTimes, res.CheckedAt)
+		cutoff := res.CheckedAt.Add(-window)
+		kept := b.FailureTimes[:0]
+		for _, t := range b.FailureTimes {
+			if t.After(cutoff) {
+				kept = append(kept, t)
+			}
+		}
+		b.FailureTimes = kept
+		b.ConsecutiveFailures = len(b.FailureTimes)
+
+		if b.ConsecutiveFailures >= threshold {
+			b.Healthy = false
+		}
+	}
+
+	justTripped := wasHealthy && !b.Healthy
+	if wasHealthy != b.Healthy {
+		if b.Healthy {
+			logger.Infow("backend available again", "backend", b.Name, "host", b.URL.Host)
+			eventHub.publish(Event{Type: "health", Backend: b.Name, Message: "healthy"})
+		} else {
+			logger.Warnw("backend not available", "backend", b.Name, "host", b.URL.Host, "failures", b.ConsecutiveFailures)
+			eventHub.publish(Event{Type: "health", Backend: b.Name, Message: "unhealthy"})
+		}
+	}
+
+	if justTripped && b.ActiveCheck != nil {
+		retryAfter := 5 * time.Second
+		if b.PassiveCheck != nil && b.PassiveCheck.RetryAfter.Duration > 0 {
+			retryAfter = b.PassiveCheck.RetryAfter.Duration
+		}
+		go func() {
+			time.Sleep(retryAfter)
+			hc.probe(b)
+		}()
+	}
+}
+
+// RecordPassiveOutcome feeds the result of a proxied request into the
+// same failure bookkeeping the active checker uses, so a run of 5xx
+// or transport errors can take a backend out of rotation before the
+// next active probe fires.
+func (hc *HealthChecker) RecordPassiveOutcome(b *Backend, success bool, latency time.Duration) {
+	hc.record(b, CheckResult{Healthy: success, Latency: latency, CheckedAt: time.Now()})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogConfig configures the zap logger from config.json. It mirrors
+// the handful of knobs operators actually reach for: verbosity,
+// human vs. machine-readable output, and where it goes.
+type LogConfig struct {
+	Level       string   `json:"level"`       // debug, info, warn, error
+	Format      string   `json:"format"`      // "json" or "console"
+	OutputPaths []string `json:"outputPaths"` // defaults to ["stdout"]
+}
+
+var logger *zap.SugaredLogger
+
+// initLogger builds the process-wide logger from the (possibly zero)
+// LogConfig found in config.json, falling back to sane defaults so
+// the proxy still logs something if it isn't configured.
+func initLogger(cfg LogConfig) (*zap.SugaredLogger, error) {
+	level := zapcore.InfoLevel
+	if cfg.Level != "" {
+		if err := level.Set(cfg.Level); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+		}
+	}
+
+	format := cfg.Format
+	if format == "" {
+		format = "console"
+	}
+
+	paths := cfg.OutputPaths
+	if len(paths) == 0 {
+		paths = []string{"stdout"}
+	}
+
+	zapCfg := zap.Config{
+		Level:            zap.NewAtomicLevelAt(level),
+		Development:      false,
+		Encoding:         format,
+		EncoderConfig:    zap.NewProductionEncoderConfig(),
+		OutputPaths:      paths,
+		ErrorOutputPaths: []string{"stderr"},
+	}
+	zapCfg.EncoderConfig.TimeKey = "ts"
+	zapCfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	l, err := zapCfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	return l.Sugar(), nil
+}
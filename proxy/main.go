@@ -3,9 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,6 +14,8 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // ------------------------------------------------------------
@@ -23,44 +23,113 @@ import (
 // ------------------------------------------------------------
 
 type Config struct {
-	Name string `json:"name"`
-	Port int    `json:"port"`
+	Name          string              `json:"name"`
+	Port          int                 `json:"port"`
+	Weight        int                 `json:"weight,omitempty"`
+	Country       string              `json:"country,omitempty"`
+	Continent     string              `json:"continent,omitempty"`
+	Args          []string            `json:"args,omitempty"`
+	BinPath       string              `json:"binPath,omitempty"`
+	BuildDir      string              `json:"buildDir,omitempty"`
+	RestartPolicy RestartPolicy       `json:"restartPolicy,omitempty"`
+	MaxRetries    int                 `json:"maxRetries,omitempty"`
+	ActiveCheck   *ActiveCheckConfig  `json:"activeCheck,omitempty"`
+	PassiveCheck  *PassiveCheckConfig `json:"passiveCheck,omitempty"`
 }
 
 type Backend struct {
 	Name           string
 	URL            *url.URL
-	Healthy        bool
-	LastSeen       time.Time
 	Cmd            *exec.Cmd
 	ActiveRequests int64
+	Weight         int
+	Country        string
+	Continent      string
+	Logs           *logRingBuffer
+
+	Args          []string
+	BinPath       string
+	BuildDir      string
+	RestartPolicy RestartPolicy
+	MaxRetries    int
+	RestartCount  int
+	ExitCode      int
+	StartedAt     time.Time
+
+	ActiveCheck  *ActiveCheckConfig
+	PassiveCheck *PassiveCheckConfig
+
+	// healthMu guards the fields below. They're written on every
+	// active/passive health check (i.e. on every proxied request) and
+	// read on every request's backend selection, so they get their
+	// own lock instead of routing through the global mu - otherwise
+	// all request handling, health-check writes, and /api/* calls
+	// would serialize behind one mutex.
+	healthMu            sync.RWMutex
+	Healthy             bool
+	LastSeen            time.Time
+	LastCheckResult     CheckResult
+	ConsecutiveFailures int
+	FailureTimes        []time.Time
 }
 
 type BackendStatus struct {
-	Name           string    `json:"name"`
-	Host           string    `json:"host"`
-	Healthy        bool      `json:"healthy"`
-	LastSeen       time.Time `json:"lastSeen"`
-	ActiveRequests int64     `json:"activeRequests"`
+	Name           string         `json:"name"`
+	Host           string         `json:"host"`
+	Healthy        bool           `json:"healthy"`
+	LastSeen       time.Time      `json:"lastSeen"`
+	ActiveRequests int64          `json:"activeRequests"`
+	LastCheck      CheckResult    `json:"lastCheck"`
+	FailureCount   int            `json:"failureCount"`
+	Country        string         `json:"country,omitempty"`
+	Continent      string         `json:"continent,omitempty"`
+	Process        ProcessMetrics `json:"process"`
 }
 
 type LoadBalancingStrategy string
 
 const (
-	StrategyRoundRobin       LoadBalancingStrategy = "round_robin"
-	StrategyLeastConnections LoadBalancingStrategy = "least_connections"
+	StrategyRoundRobin         LoadBalancingStrategy = "round_robin"
+	StrategyLeastConnections   LoadBalancingStrategy = "least_connections"
+	StrategyWeightedRoundRobin LoadBalancingStrategy = "weighted_round_robin"
+	StrategyRandom             LoadBalancingStrategy = "random"
+	StrategyIPHash             LoadBalancingStrategy = "ip_hash"
+	StrategyURIHash            LoadBalancingStrategy = "uri_hash"
+	StrategyHeaderHash         LoadBalancingStrategy = "header_hash"
+	StrategyFirstAvailable     LoadBalancingStrategy = "first_available"
+	StrategyGeo                LoadBalancingStrategy = "geo"
 )
 
+var validStrategies = []LoadBalancingStrategy{
+	StrategyRoundRobin, StrategyLeastConnections, StrategyWeightedRoundRobin,
+	StrategyRandom, StrategyIPHash, StrategyURIHash, StrategyHeaderHash,
+	StrategyFirstAvailable, StrategyGeo,
+}
+
+// StrategyParams holds the extra, strategy-specific configuration
+// that doesn't fit in the plain strategy name, e.g. which header to
+// hash on for header_hash. It is persisted alongside the strategy
+// name in strategy.json.
+type StrategyParams struct {
+	HeaderName string `json:"headerName,omitempty"`
+}
+
 // ------------------------------------------------------------
 // globals
 // ------------------------------------------------------------
 
 var (
-	backends    []*Backend
-	counter     uint64
-	mu          sync.RWMutex
-	proxyActive atomic.Bool
-	strategy    LoadBalancingStrategy = StrategyRoundRobin
+	backends       []*Backend
+	counter        uint64
+	mu             sync.RWMutex
+	proxyActive    atomic.Bool
+	strategy       LoadBalancingStrategy = StrategyRoundRobin
+	strategyParams StrategyParams
+	checker        *HealthChecker
+	eventHub       = newHub()
+	flushInterval  time.Duration
+	geoip          *GeoIP
+	supervisor     *Supervisor
 )
 
 // ------------------------------------------------------------
@@ -69,23 +138,42 @@ var (
 
 func main() {
 	// load config.json
-	cfgs, err := loadConfig("config.json")
+	root, err := loadConfig("config.json")
 	if err != nil {
 		log.Fatalf("Issue reading config.json: %v", err)
 	}
 
+	l, err := initLogger(root.Logging)
+	if err != nil {
+		log.Fatalf("Issue initializing logger: %v", err)
+	}
+	logger = l
+	defer logger.Sync()
+
+	sharedTransport = newSharedTransport(root.Transport)
+	flushInterval = root.Transport.FlushInterval.Duration
+	configureOrigins(root.Events.AllowedOrigins)
+
+	g, err := newGeoIP(root.Geo)
+	if err != nil {
+		logger.Fatalf("Issue loading GeoIP database: %v", err)
+	}
+	geoip = g
+
 	// load strategy
 	loadStrategy()
 
 	// start backends
-	for _, cfg := range cfgs {
+	supervisor = newSupervisor()
+	for _, cfg := range root.Backends {
 		b := newBackend(cfg)
 		backends = append(backends, b)
-		startBackend(b)
+		supervisor.Start(b)
 	}
 
 	// start health checks
-	go healthMonitor()
+	checker = newHealthChecker()
+	checker.Run(backends)
 
 	// Signal-Handling for clean exit
 	stop := make(chan os.Signal, 1)
@@ -109,10 +197,13 @@ func main() {
 	http.HandleFunc("/api/proxy/resume", handleProxyResume)
 	http.HandleFunc("/api/proxy/state", handleProxyState)
 	http.HandleFunc("/api/proxy/strategy", handleProxyStrategy)
+	http.HandleFunc("/api/events", handleEventsWS)
+	http.HandleFunc("/api/logs/", handleBackendLogs)
+	http.HandleFunc("/api/backends/", handleBackendLogs)
 
-	log.Println("reverse proxy running on port :8080")
+	logger.Info("reverse proxy running on port :8080")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
 }
 
@@ -127,12 +218,21 @@ func handleAPIStatus(w http.ResponseWriter, r *http.Request) {
 
 	statusList := make([]BackendStatus, 0, len(backends))
 	for _, b := range backends {
+		b.healthMu.RLock()
+		healthy, lastSeen, lastCheck, failureCount := b.Healthy, b.LastSeen, b.LastCheckResult, b.ConsecutiveFailures
+		b.healthMu.RUnlock()
+
 		statusList = append(statusList, BackendStatus{
 			Name:           b.Name,
 			Host:           b.URL.Host,
-			Healthy:        b.Healthy,
-			LastSeen:       b.LastSeen,
+			Healthy:        healthy,
+			LastSeen:       lastSeen,
 			ActiveRequests: atomic.LoadInt64(&b.ActiveRequests),
+			LastCheck:      lastCheck,
+			FailureCount:   failureCount,
+			Country:        b.Country,
+			Continent:      b.Continent,
+			Process:        metricsLocked(b),
 		})
 	}
 
@@ -140,13 +240,14 @@ func handleAPIStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleRequest(w http.ResponseWriter, r *http.Request) {
-	reqID := time.Now().UnixNano()
+	reqID := uuid.NewString()
+	r.Header.Set("X-Request-ID", reqID)
 	if !proxyActive.Load() {
 		http.Error(w, "Proxy is currently paused", http.StatusServiceUnavailable)
 		return
 	}
 
-	b := getNextHealthyBackend()
+	b := getNextHealthyBackend(r)
 	if b == nil {
 		http.Error(w, "No healthy backend available", http.StatusServiceUnavailable)
 		return
@@ -154,37 +255,36 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 
 	// ActiveRequests is already incremented in getNextHealthyBackend
 	currentActive := atomic.LoadInt64(&b.ActiveRequests)
-	log.Printf("[%d] Starting request to '%s'. Active: %d", reqID, b.Name, currentActive)
+	clientCountry := ""
+	if geoip != nil {
+		clientCountry = geoip.Country(geoip.ResolveClientIP(r))
+	}
+	logger.Infow("starting request", "requestId", reqID, "backend", b.Name, "strategy", strategy,
+		"active", currentActive, "clientCountry", clientCountry)
+	eventHub.publish(Event{Type: "request", Backend: b.Name, Message: "start"})
 
+	start := time.Now()
 	defer func() {
 		newActive := atomic.AddInt64(&b.ActiveRequests, -1)
-		log.Printf("[%d] Finished request to '%s'. Active: %d", reqID, b.Name, newActive)
+		logger.Infow("finished request", "requestId", reqID, "backend", b.Name, "active", newActive,
+			"duration_ms", time.Since(start).Milliseconds())
+		eventHub.publish(Event{Type: "request", Backend: b.Name, Message: "finish"})
 	}()
 
-	r.URL.Scheme = b.URL.Scheme
-	r.URL.Host = b.URL.Host
-	r.Host = b.URL.Host
-	r.URL.Path = strings.TrimPrefix(r.URL.Path, "/proxy")
-
-	resp, err := http.DefaultTransport.RoundTrip(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		handleWebSocketUpgrade(w, r, b)
 		return
 	}
-	defer resp.Body.Close()
 
-	for k, v := range resp.Header {
-		for _, vv := range v {
-			w.Header().Add(k, vv)
-		}
-	}
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+	proxy := newReverseProxy(b, flushInterval)
+	proxy.ModifyResponse = recordOutcomeModifier(proxy.ModifyResponse, b, start)
+	proxy.ErrorHandler = recordErrorHandler(proxy.ErrorHandler, b, start)
+	proxy.ServeHTTP(w, r)
 }
 
 func handleProxyPause(w http.ResponseWriter, r *http.Request) {
 	proxyActive.Store(false)
-	log.Println("Reverse Proxy disabled")
+	logger.Info("Reverse Proxy disabled")
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "paused",
 		"message": "Proxy disabled",
@@ -193,7 +293,7 @@ func handleProxyPause(w http.ResponseWriter, r *http.Request) {
 
 func handleProxyResume(w http.ResponseWriter, r *http.Request) {
 	proxyActive.Store(true)
-	log.Println("Reverse Proxy enabled")
+	logger.Info("Reverse Proxy enabled")
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "active",
 		"message": "Proxy enabled",
@@ -217,35 +317,41 @@ func handleProxyStrategy(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method == http.MethodPost {
 		var req struct {
-			Strategy string `json:"strategy"`
+			Strategy string         `json:"strategy"`
+			Params   StrategyParams `json:"params"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		mu.Lock()
-		switch LoadBalancingStrategy(req.Strategy) {
-		case StrategyRoundRobin:
-			strategy = StrategyRoundRobin
-		case StrategyLeastConnections:
-			strategy = StrategyLeastConnections
-		default:
-			mu.Unlock()
+		newStrategy := LoadBalancingStrategy(req.Strategy)
+		if !isValidStrategy(newStrategy) {
 			http.Error(w, "Invalid strategy", http.StatusBadRequest)
 			return
 		}
-		saveStrategy(strategy)
-		log.Printf("Load balancing strategy changed to: %s", strategy)
+		if newStrategy == StrategyHeaderHash && req.Params.HeaderName == "" {
+			http.Error(w, "header_hash requires params.headerName", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		strategy = newStrategy
+		strategyParams = req.Params
+		saveStrategy(strategy, strategyParams)
+		logger.Infow("load balancing strategy changed", "strategy", strategy)
+		eventHub.publish(Event{Type: "strategy", Message: string(strategy), Data: strategyParams})
 		mu.Unlock()
 	}
 
 	mu.RLock()
 	currentStrategy := strategy
+	currentParams := strategyParams
 	mu.RUnlock()
 
-	json.NewEncoder(w).Encode(map[string]string{
+	json.NewEncoder(w).Encode(map[string]interface{}{
 		"strategy": string(currentStrategy),
+		"params":   currentParams,
 	})
 }
 
@@ -256,29 +362,28 @@ func handleStartOrRestartBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
+	b := findBackend(name)
+	if b == nil {
+		http.Error(w, fmt.Sprintf("Backend '%s' nicht gefunden", name), http.StatusNotFound)
+		return
+	}
 
-	for _, b := range backends {
-		if b.Name == name {
-			// If process running, restart
-			if b.Cmd != nil && b.Cmd.Process != nil {
-				log.Printf("Backend '%s' restarting ...", b.Name)
-				b.Cmd.Process.Kill()
-			} else {
-				log.Printf("Backend '%s' starting ...", b.Name)
-			}
-
-			startBackend(b)
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": fmt.Sprintf("Backend '%s' gestartet/neu gestartet", b.Name),
-			})
-			return
-		}
+	mu.RLock()
+	running := b.Cmd != nil && b.Cmd.Process != nil
+	mu.RUnlock()
+
+	if running {
+		logger.Infow("backend restarting", "backend", b.Name)
+		supervisor.Stop(b)
+	} else {
+		logger.Infow("backend starting", "backend", b.Name)
 	}
+	supervisor.Start(b)
 
-	http.Error(w, fmt.Sprintf("Backend '%s' nicht gefunden", name), http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": fmt.Sprintf("Backend '%s' gestartet/neu gestartet", b.Name),
+	})
 }
 
 func handleStopBackend(w http.ResponseWriter, r *http.Request) {
@@ -288,91 +393,95 @@ func handleStopBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
+	b := findBackend(name)
+	if b == nil {
+		http.Error(w, fmt.Sprintf("Backend '%s' nicht gefunden oder bereits gestoppt", name), http.StatusNotFound)
+		return
+	}
+
+	if err := supervisor.Stop(b); err != nil {
+		http.Error(w, fmt.Sprintf("Fehler beim Stoppen von %s: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+
+	b.healthMu.Lock()
+	b.Healthy = false
+	b.healthMu.Unlock()
+	logger.Infow("backend manually stopped", "backend", b.Name)
+	eventHub.publish(Event{Type: "lifecycle", Backend: b.Name, Message: "stopped"})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": fmt.Sprintf("Backend '%s' gestoppt", b.Name),
+	})
+}
 
+func findBackend(name string) *Backend {
+	mu.RLock()
+	defer mu.RUnlock()
 	for _, b := range backends {
 		if b.Name == name {
-			if b.Cmd != nil && b.Cmd.Process != nil {
-				err := b.Cmd.Process.Kill()
-				if err != nil {
-					http.Error(w, fmt.Sprintf("Fehler beim Stoppen von %s: %v", name, err), http.StatusInternalServerError)
-					return
-				}
-				b.Healthy = false
-				log.Printf(" Backend '%s' manually stopped", b.Name)
-				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(map[string]string{
-					"message": fmt.Sprintf("Backend '%s' gestoppt", b.Name),
-				})
-				return
-			}
+			return b
 		}
 	}
-
-	http.Error(w, fmt.Sprintf("Backend '%s' nicht gefunden oder bereits gestoppt", name), http.StatusNotFound)
+	return nil
 }
 
 // ------------------------------------------------------------
 // helpers
 // ------------------------------------------------------------
 
-func loadConfig(path string) ([]Config, error) {
+// RootConfig is the shape of config.json: logging, transport, geo and
+// events blocks plus the list of backends to manage.
+type RootConfig struct {
+	Logging   LogConfig       `json:"logging"`
+	Transport TransportConfig `json:"transport"`
+	Geo       GeoConfig       `json:"geo"`
+	Events    EventsConfig    `json:"events"`
+	Backends  []Config        `json:"backends"`
+}
+
+func loadConfig(path string) (RootConfig, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return RootConfig{}, err
 	}
 	defer file.Close()
 
-	var cfg []Config
-	err = json.NewDecoder(file).Decode(&cfg)
-	return cfg, err
+	var root RootConfig
+	err = json.NewDecoder(file).Decode(&root)
+	return root, err
 }
 
 func newBackend(cfg Config) *Backend {
 	u, err := url.Parse(fmt.Sprintf("http://localhost:%d", cfg.Port))
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
-	return &Backend{Name: cfg.Name, URL: u}
-}
-
-func startBackend(b *Backend) {
-	port := b.URL.Port()
-
-	// check if binary exists, else build
-	if _, err := os.Stat("../backend/backend.exe"); os.IsNotExist(err) {
-		log.Println("Building backend binary")
-		buildCmd := exec.Command("go", "build", "-o", "backend.exe", "backend.go")
-		buildCmd.Dir = "../backend"
-		buildCmd.Stdout = log.Writer()
-		buildCmd.Stderr = log.Writer()
-		if err := buildCmd.Run(); err != nil {
-			log.Fatalf("backend build error: %v", err)
-		}
-	}
-
-	// start executable
-	cmd := exec.Command("../backend/backend.exe")
-	cmd.Env = append(cmd.Env, fmt.Sprintf("PORT=%s", port))
-	cmd.Stdout = log.Writer()
-	cmd.Stderr = log.Writer()
-
-	if err := cmd.Start(); err != nil {
-		log.Printf("Backend '%s' (%s) couldn't start: %v", b.Name, port, err)
-		return
+	b := &Backend{
+		Name:          cfg.Name,
+		URL:           u,
+		Weight:        cfg.Weight,
+		Country:       cfg.Country,
+		Continent:     cfg.Continent,
+		Args:          cfg.Args,
+		BinPath:       cfg.BinPath,
+		BuildDir:      cfg.BuildDir,
+		RestartPolicy: cfg.RestartPolicy,
+		MaxRetries:    cfg.MaxRetries,
+		ActiveCheck:   cfg.ActiveCheck,
+		PassiveCheck:  cfg.PassiveCheck,
 	}
-	b.Cmd = cmd
-	log.Printf("Backend '%s' automatically started (Port %s)", b.Name, port)
+	b.Logs = newLogRingBuffer(b.Name, 500, eventHub)
+	return b
 }
 
-func getNextHealthyBackend() *Backend {
-	mu.Lock()
-	defer mu.Unlock()
-
+func getNextHealthyBackend(r *http.Request) *Backend {
 	var healthy []*Backend
 	for _, b := range backends {
-		if b.Healthy {
+		b.healthMu.RLock()
+		isHealthy := b.Healthy
+		b.healthMu.RUnlock()
+		if isHealthy {
 			healthy = append(healthy, b)
 		}
 	}
@@ -380,106 +489,79 @@ func getNextHealthyBackend() *Backend {
 		return nil
 	}
 
-	if strategy == StrategyLeastConnections {
-		var best *Backend
-		minActive := int64(-1)
-
-		for _, b := range healthy {
-			active := atomic.LoadInt64(&b.ActiveRequests)
-			if minActive == -1 || active < minActive {
-				minActive = active
-				best = b
-			}
-		}
-		if best != nil {
-			val := atomic.AddInt64(&best.ActiveRequests, 1)
-			log.Printf("Selected '%s' (LeastConn). New Active: %d", best.Name, val)
-		}
-		return best
+	mu.RLock()
+	s := strategy
+	b := policyFor(s).Select(healthy, r)
+	mu.RUnlock()
+	if b == nil {
+		return nil
 	}
-
-	// Default: Round Robin
-	index := int(atomic.AddUint64(&counter, 1)) % len(healthy)
-	b := healthy[index]
 	val := atomic.AddInt64(&b.ActiveRequests, 1)
-	log.Printf("Selected '%s' (RR). New Active: %d", b.Name, val)
+	logger.Infow("selected backend", "backend", b.Name, "strategy", s, "active", val)
 	return b
 }
 
-func saveStrategy(s LoadBalancingStrategy) {
+func saveStrategy(s LoadBalancingStrategy, params StrategyParams) {
 	file, err := os.Create("strategy.json")
 	if err != nil {
-		log.Printf("Failed to save strategy: %v", err)
+		logger.Errorw("failed to save strategy", "error", err)
 		return
 	}
 	defer file.Close()
 
-	json.NewEncoder(file).Encode(map[string]string{"strategy": string(s)})
+	json.NewEncoder(file).Encode(map[string]interface{}{
+		"strategy": string(s),
+		"params":   params,
+	})
 }
 
 func loadStrategy() {
 	file, err := os.Open("strategy.json")
 	if err != nil {
 		if !os.IsNotExist(err) {
-			log.Printf("Failed to load strategy: %v", err)
+			logger.Errorw("failed to load strategy", "error", err)
 		}
 		return
 	}
 	defer file.Close()
 
 	var data struct {
-		Strategy string `json:"strategy"`
+		Strategy string         `json:"strategy"`
+		Params   StrategyParams `json:"params"`
 	}
 	if err := json.NewDecoder(file).Decode(&data); err != nil {
-		log.Printf("Failed to decode strategy: %v", err)
+		logger.Errorw("failed to decode strategy", "error", err)
 		return
 	}
 
-	switch LoadBalancingStrategy(data.Strategy) {
-	case StrategyRoundRobin:
-		strategy = StrategyRoundRobin
-	case StrategyLeastConnections:
-		strategy = StrategyLeastConnections
+	if isValidStrategy(LoadBalancingStrategy(data.Strategy)) {
+		strategy = LoadBalancingStrategy(data.Strategy)
+		strategyParams = data.Params
 	}
-	log.Printf("Loaded strategy from file: %s", strategy)
+	logger.Infow("loaded strategy from file", "strategy", strategy)
 }
 
-// ------------------------------------------------------------
-// monitoring + cleanup
-// ------------------------------------------------------------
-
-func healthMonitor() {
-	for {
-		for _, b := range backends {
-			addr := b.URL.Host
-			conn, err := net.DialTimeout("tcp", addr, 800*time.Millisecond)
-			mu.Lock()
-			if err != nil {
-				if b.Healthy {
-					log.Printf("%s (%s) is not available", b.Name, addr)
-				}
-				b.Healthy = false
-			} else {
-				conn.Close()
-				if !b.Healthy {
-					log.Printf("%s (%s) is available again", b.Name, addr)
-				}
-				b.Healthy = true
-				b.LastSeen = time.Now()
-			}
-			mu.Unlock()
+func isValidStrategy(s LoadBalancingStrategy) bool {
+	for _, v := range validStrategies {
+		if s == v {
+			return true
 		}
-		time.Sleep(3 * time.Second)
 	}
+	return false
 }
 
+// ------------------------------------------------------------
+// cleanup
+// ------------------------------------------------------------
+
 func cleanup() {
-	log.Println("proxy shut-down, disabling backends ...")
+	logger.Info("proxy shut-down, disabling backends ...")
 	for _, b := range backends {
-		if b.Cmd != nil && b.Cmd.Process != nil {
-			b.Cmd.Process.Kill()
-			log.Printf("Backend '%s' stopped", b.Name)
+		if err := supervisor.Stop(b); err != nil {
+			logger.Errorw("failed to stop backend cleanly", "backend", b.Name, "error", err)
+			continue
 		}
+		logger.Infow("backend stopped", "backend", b.Name)
 	}
-	log.Println("All backends shut-down.")
+	logger.Info("all backends shut down")
 }
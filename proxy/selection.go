@@ -0,0 +1,194 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+)
+
+// ------------------------------------------------------------
+// policy interface
+// ------------------------------------------------------------
+
+// SelectionPolicy picks one backend from a pre-filtered list of
+// healthy candidates for a given request. Implementations must be
+// safe to call with mu already held for reading; they should not
+// take mu themselves.
+type SelectionPolicy interface {
+	Name() LoadBalancingStrategy
+	Select(backends []*Backend, r *http.Request) *Backend
+}
+
+func policyFor(s LoadBalancingStrategy) SelectionPolicy {
+	switch s {
+	case StrategyLeastConnections:
+		return leastConnectionsPolicy{}
+	case StrategyWeightedRoundRobin:
+		return weightedRoundRobinPolicy{}
+	case StrategyRandom:
+		return randomPolicy{}
+	case StrategyIPHash:
+		return ipHashPolicy{}
+	case StrategyURIHash:
+		return uriHashPolicy{}
+	case StrategyHeaderHash:
+		return headerHashPolicy{header: strategyParams.HeaderName}
+	case StrategyFirstAvailable:
+		return firstAvailablePolicy{}
+	case StrategyGeo:
+		return geoPolicy{}
+	default:
+		return roundRobinPolicy{}
+	}
+}
+
+// ------------------------------------------------------------
+// implementations
+// ------------------------------------------------------------
+
+type roundRobinPolicy struct{}
+
+func (roundRobinPolicy) Name() LoadBalancingStrategy { return StrategyRoundRobin }
+
+func (roundRobinPolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	index := int(atomic.AddUint64(&counter, 1)) % len(backends)
+	return backends[index]
+}
+
+type leastConnectionsPolicy struct{}
+
+func (leastConnectionsPolicy) Name() LoadBalancingStrategy { return StrategyLeastConnections }
+
+func (leastConnectionsPolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	var best *Backend
+	minActive := int64(-1)
+	for _, b := range backends {
+		active := atomic.LoadInt64(&b.ActiveRequests)
+		if minActive == -1 || active < minActive {
+			minActive = active
+			best = b
+		}
+	}
+	return best
+}
+
+// weightedRoundRobinPolicy picks among backends proportionally to
+// their configured Weight (defaulting to 1), using the shared
+// request counter so the distribution stays deterministic.
+type weightedRoundRobinPolicy struct{}
+
+func (weightedRoundRobinPolicy) Name() LoadBalancingStrategy { return StrategyWeightedRoundRobin }
+
+func (weightedRoundRobinPolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	total := 0
+	for _, b := range backends {
+		total += weightOf(b)
+	}
+	if total <= 0 {
+		return backends[0]
+	}
+
+	target := int(atomic.AddUint64(&counter, 1)) % total
+	for _, b := range backends {
+		target -= weightOf(b)
+		if target < 0 {
+			return b
+		}
+	}
+	return backends[len(backends)-1]
+}
+
+func weightOf(b *Backend) int {
+	if b.Weight <= 0 {
+		return 1
+	}
+	return b.Weight
+}
+
+type randomPolicy struct{}
+
+func (randomPolicy) Name() LoadBalancingStrategy { return StrategyRandom }
+
+func (randomPolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	return backends[rand.Intn(len(backends))]
+}
+
+// ipHashPolicy sends all traffic from a given client IP to the same
+// backend, so session-affine or cache-warm clients stick.
+type ipHashPolicy struct{}
+
+func (ipHashPolicy) Name() LoadBalancingStrategy { return StrategyIPHash }
+
+func (ipHashPolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	return hashPick(backends, clientIP(r))
+}
+
+// uriHashPolicy routes by request path so a given URI is always
+// served from the same backend, which helps local per-backend caches.
+type uriHashPolicy struct{}
+
+func (uriHashPolicy) Name() LoadBalancingStrategy { return StrategyURIHash }
+
+func (uriHashPolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	return hashPick(backends, r.URL.Path)
+}
+
+// headerHashPolicy hashes a configurable request header, falling
+// back to round robin when the header is absent.
+type headerHashPolicy struct {
+	header string
+}
+
+func (headerHashPolicy) Name() LoadBalancingStrategy { return StrategyHeaderHash }
+
+func (p headerHashPolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	if p.header == "" {
+		return roundRobinPolicy{}.Select(backends, r)
+	}
+	v := r.Header.Get(p.header)
+	if v == "" {
+		return roundRobinPolicy{}.Select(backends, r)
+	}
+	return hashPick(backends, v)
+}
+
+type firstAvailablePolicy struct{}
+
+func (firstAvailablePolicy) Name() LoadBalancingStrategy { return StrategyFirstAvailable }
+
+func (firstAvailablePolicy) Select(backends []*Backend, r *http.Request) *Backend {
+	return backends[0]
+}
+
+func hashPick(backends []*Backend, key string) *Backend {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	index := int(h.Sum32()) % len(backends)
+	if index < 0 {
+		index += len(backends)
+	}
+	return backends[index]
+}
+
+// clientIP returns the key ipHashPolicy hashes on. It deliberately
+// ignores X-Forwarded-For: that header is client-supplied and
+// unvalidated here, so trusting it would let any client pick its own
+// backend by spoofing whatever "IP" it wants. r.RemoteAddr is the
+// actual TCP peer and can't be forged.
+func clientIP(r *http.Request) string {
+	host := r.RemoteAddr
+	if idx := lastIndexByte(host, ':'); idx != -1 {
+		return host[:idx]
+	}
+	return host
+}
+
+func lastIndexByte(s string, b byte) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == b {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+)
+
+// hopHeaders are stripped before forwarding a request or response,
+// per RFC 7230 section 6.1 - they're meaningful only for the
+// connection between a client and the immediate next hop, not the
+// whole proxy chain.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func stripHopHeaders(h http.Header) {
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// TransportConfig configures the shared outbound http.Transport used
+// for every backend, and the FlushInterval reverse proxies use for
+// streaming/SSE responses.
+type TransportConfig struct {
+	MaxIdleConns          int      `json:"maxIdleConns"`
+	MaxIdleConnsPerHost   int      `json:"maxIdleConnsPerHost"`
+	DialTimeout           Duration `json:"dialTimeout"`
+	ResponseHeaderTimeout Duration `json:"responseHeaderTimeout"`
+	FlushInterval         Duration `json:"flushInterval"`
+}
+
+var sharedTransport http.RoundTripper
+
+func newSharedTransport(cfg TransportConfig) *http.Transport {
+	dialTimeout := cfg.DialTimeout.Duration
+	if dialTimeout <= 0 {
+		dialTimeout = 10 * time.Second
+	}
+	maxIdle := cfg.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = 100
+	}
+	maxIdlePerHost := cfg.MaxIdleConnsPerHost
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = 10
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout: dialTimeout,
+		}).DialContext,
+		MaxIdleConns:          maxIdle,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout.Duration,
+		IdleConnTimeout:       90 * time.Second,
+	}
+}
+
+// newReverseProxy builds a *httputil.ReverseProxy for a single
+// backend that strips hop-by-hop headers and adds the usual
+// X-Forwarded-* headers. It records no passive health outcome itself;
+// handleRequest wraps its ModifyResponse and ErrorHandler with
+// recordOutcomeModifier and recordErrorHandler for that.
+func newReverseProxy(b *Backend, flushInterval time.Duration) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Transport:     sharedTransport,
+		FlushInterval: flushInterval,
+		Director: func(req *http.Request) {
+			clientHost := req.Host
+			stripHopHeaders(req.Header)
+
+			req.URL.Scheme = b.URL.Scheme
+			req.URL.Host = b.URL.Host
+			req.Host = b.URL.Host
+			req.URL.Path = strings.TrimPrefix(req.URL.Path, "/proxy")
+
+			req.Header.Set("X-Forwarded-Host", clientHost)
+			req.Header.Set("X-Forwarded-Proto", schemeOf(req))
+			if fwd := req.Header.Get("X-Forwarded-For"); fwd != "" {
+				req.Header.Set("X-Forwarded-For", fwd+", "+clientIP(req))
+			} else {
+				req.Header.Set("X-Forwarded-For", clientIP(req))
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			stripHopHeaders(resp.Header)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Errorw("proxy transport error", "backend", b.Name, "error", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+}
+
+// recordOutcomeModifier wraps an existing ModifyResponse (if any) so
+// the proxy's passive health bookkeeping sees every response that
+// comes back through a reverse proxy. WebSocket upgrades, handled by
+// handleWebSocketUpgrade, are not recorded.
+func recordOutcomeModifier(next func(*http.Response) error, b *Backend, start time.Time) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		checker.RecordPassiveOutcome(b, resp.StatusCode < 500, time.Since(start))
+		if next != nil {
+			return next(resp)
+		}
+		return nil
+	}
+}
+
+// recordErrorHandler wraps an existing ErrorHandler (if any) so a
+// dial/timeout failure against the backend also counts as a passive
+// failure.
+func recordErrorHandler(next func(http.ResponseWriter, *http.Request, error), b *Backend, start time.Time) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		checker.RecordPassiveOutcome(b, false, time.Since(start))
+		if next != nil {
+			next(w, r, err)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+}
+
+func schemeOf(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// handleWebSocketUpgrade proxies an `Upgrade: websocket` request by
+// hijacking the client connection and dialing the backend directly,
+// then copying bytes in both directions - httputil.ReverseProxy
+// doesn't expose raw hijacking, so upgrades are handled by hand.
+func handleWebSocketUpgrade(w http.ResponseWriter, r *http.Request, b *Backend) {
+	backendConn, err := net.DialTimeout("tcp", b.URL.Host, 10*time.Second)
+	if err != nil {
+		http.Error(w, "failed to reach backend", http.StatusBadGateway)
+		return
+	}
+	defer backendConn.Close()
+
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, "/proxy")
+	r.Host = b.URL.Host
+	if err := r.Write(backendConn); err != nil {
+		http.Error(w, "failed to forward websocket handshake", http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket upgrade not supported", http.StatusInternalServerError)
+		return
+	}
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, "failed to hijack connection", http.StatusInternalServerError)
+		return
+	}
+	defer clientConn.Close()
+
+	if clientBuf.Reader.Buffered() > 0 {
+		if _, err := io.CopyN(backendConn, clientBuf.Reader, int64(clientBuf.Reader.Buffered())); err != nil {
+			return
+		}
+	}
+
+	done := make(chan struct{}, 2)
+	go proxyCopy(backendConn, clientConn, done)
+	go proxyCopy(clientConn, backendConn, done)
+	<-done
+}
+
+func proxyCopy(dst, src net.Conn, done chan struct{}) {
+	io.Copy(dst, src)
+	done <- struct{}{}
+}